@@ -2,31 +2,52 @@ package api
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/gotify/location"
 	"github.com/gotify/server/auth"
 	"github.com/gotify/server/model"
+	"github.com/gotify/server/pagination"
+	"github.com/gotify/server/scheduler"
 )
 
 // The MessageDatabase interface for encapsulating database access.
 type MessageDatabase interface {
 	GetMessagesByApplication(appID uint) ([]*model.Message, error)
 	GetMessagesByApplicationSince(appID uint, limit int, since uint) ([]*model.Message, error)
+	GetMessagesByApplicationFiltered(appID uint, limit int, since uint, direction string, filter model.MessageFilter) ([]*model.Message, error)
 	GetApplicationByID(id uint) (*model.Application, error)
 	GetMessagesByUser(userID uint) ([]*model.Message, error)
 	GetMessagesByUserSince(userID uint, limit int, since uint) ([]*model.Message, error)
+	GetMessagesFiltered(userID uint, limit int, since uint, direction string, filter model.MessageFilter) ([]*model.Message, error)
 	DeleteMessageByID(id uint) error
 	GetMessageByID(id uint) (*model.Message, error)
 	DeleteMessagesByUser(userID uint) error
 	DeleteMessagesByApplication(applicationID uint) error
 	CreateMessage(message *model.Message) error
 	GetApplicationByToken(token string) (*model.Application, error)
+	GetMessageByIdempotencyKey(appID uint, key string) (*model.Message, error)
+	StoreIdempotencyKey(appID uint, key string, msgID uint, expiresAt time.Time) error
+	GetDueScheduledMessages(now time.Time, limit int) ([]*model.Message, error)
+	MarkMessageDelivered(id uint) error
+	// ScheduleNextOccurrence bumps a recurring (Cron) message's SendAt to its
+	// next occurrence, keeping it in MessageStateScheduled.
+	ScheduleNextOccurrence(id uint, nextSendAt time.Time) error
+	GetScheduledMessagesByUser(userID uint) ([]*model.Message, error)
+	GetUnackedMessagesByUser(userID uint) ([]*model.Message, error)
+	AckMessage(userID, msgID uint) error
 }
 
+// idempotencyKeyTTL is how long a stored Idempotency-Key stays valid. A
+// repeated create after this window creates a new message rather than
+// replaying the old one.
+const idempotencyKeyTTL = 24 * time.Hour
+
 // Notifier notifies when a new message was created.
 type Notifier interface {
 	Notify(userID uint, event model.Event)
@@ -36,11 +57,151 @@ type Notifier interface {
 type MessageAPI struct {
 	DB       MessageDatabase
 	Notifier Notifier
+	// PagingSecret signs the opaque next_page_token/prev_page_token cursors
+	// so clients cannot forge or tamper with them.
+	PagingSecret []byte
 }
 
 type pagingParams struct {
 	Limit int  `form:"limit" binding:"min=1,max=200"`
+	// Deprecated: use NextPageToken instead, kept around for one release so
+	// existing clients keep working.
 	Since uint `form:"since" binding:"min=0"`
+	// NextPageToken is the opaque cursor returned as Paging.NextPageToken.
+	// When present it takes precedence over Since/Limit/Direction, which it
+	// carries internally, so a page is self-consistent with the one before it.
+	NextPageToken string `form:"next_page_token"`
+	// Direction is always the canonical listing direction, even for a page
+	// fetched via a backward (prev_page_token) cursor. Use scanDirection to
+	// actually query the database.
+	Direction string `form:"direction" binding:"omitempty,oneof=asc desc"`
+
+	cursor        *pagination.CursorToken
+	scanDirection string
+}
+
+// filterParams are the query params accepted by the list endpoints to
+// narrow down which messages are returned. They're composed into SQL by the
+// MessageDatabase implementation rather than filtered in Go.
+type filterParams struct {
+	PriorityMin    *int   `form:"priority_min" binding:"omitempty,min=0,max=10"`
+	PriorityMax    *int   `form:"priority_max" binding:"omitempty,min=0,max=10"`
+	SinceDate      string `form:"since_date"`
+	UntilDate      string `form:"until_date"`
+	Search         string `form:"search"`
+	ApplicationIDs string `form:"application_ids"`
+}
+
+// toModel parses and validates the raw query params into a model.MessageFilter.
+func (f *filterParams) toModel() (model.MessageFilter, error) {
+	filter := model.MessageFilter{
+		PriorityMin: f.PriorityMin,
+		PriorityMax: f.PriorityMax,
+		Search:      f.Search,
+	}
+	if f.SinceDate != "" {
+		t, err := time.Parse(time.RFC3339, f.SinceDate)
+		if err != nil {
+			return filter, fmt.Errorf("since_date: %s", err)
+		}
+		filter.SinceDate = &t
+	}
+	if f.UntilDate != "" {
+		t, err := time.Parse(time.RFC3339, f.UntilDate)
+		if err != nil {
+			return filter, fmt.Errorf("until_date: %s", err)
+		}
+		filter.UntilDate = &t
+	}
+	if f.ApplicationIDs != "" {
+		for _, raw := range strings.Split(f.ApplicationIDs, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(raw), 10, 64)
+			if err != nil {
+				return filter, fmt.Errorf("application_ids: %s", err)
+			}
+			filter.ApplicationIDs = append(filter.ApplicationIDs, uint(id))
+		}
+	}
+	if filter.PriorityMin != nil && filter.PriorityMax != nil && *filter.PriorityMin > *filter.PriorityMax {
+		return filter, fmt.Errorf("priority_min: must not be greater than priority_max")
+	}
+	if filter.SinceDate != nil && filter.UntilDate != nil && filter.SinceDate.After(*filter.UntilDate) {
+		return filter, fmt.Errorf("since_date: must not be after until_date")
+	}
+	return filter, nil
+}
+
+// withFilter binds filterParams from the query string and passes the parsed
+// model.MessageFilter to f, aborting the request with 400 if the params
+// don't validate or parse.
+func (a *MessageAPI) withFilter(ctx *gin.Context, f func(filter model.MessageFilter)) {
+	params := &filterParams{}
+	if err := ctx.ShouldBindQuery(params); err != nil {
+		ctx.AbortWithError(400, err)
+		return
+	}
+	filter, err := params.toModel()
+	if err != nil {
+		ctx.AbortWithError(400, err)
+		return
+	}
+	f(filter)
+}
+
+func filterToToken(filter model.MessageFilter) *pagination.Filter {
+	if filter.IsEmpty() {
+		return nil
+	}
+	token := &pagination.Filter{
+		PriorityMin:    filter.PriorityMin,
+		PriorityMax:    filter.PriorityMax,
+		Search:         filter.Search,
+		ApplicationIDs: filter.ApplicationIDs,
+	}
+	if filter.SinceDate != nil {
+		token.SinceDate = filter.SinceDate.Format(time.RFC3339)
+	}
+	if filter.UntilDate != nil {
+		token.UntilDate = filter.UntilDate.Format(time.RFC3339)
+	}
+	return token
+}
+
+// oppositeDirection returns the direction that walks back towards the start
+// of the listing that produced direction, i.e. the scan direction for a
+// backward (prev_page_token) cursor.
+func oppositeDirection(direction string) string {
+	if direction == pagination.DirectionAsc {
+		return pagination.DirectionDesc
+	}
+	return pagination.DirectionAsc
+}
+
+func filterFromToken(token *pagination.Filter) (model.MessageFilter, error) {
+	if token == nil {
+		return model.MessageFilter{}, nil
+	}
+	filter := model.MessageFilter{
+		PriorityMin:    token.PriorityMin,
+		PriorityMax:    token.PriorityMax,
+		Search:         token.Search,
+		ApplicationIDs: token.ApplicationIDs,
+	}
+	if token.SinceDate != "" {
+		t, err := time.Parse(time.RFC3339, token.SinceDate)
+		if err != nil {
+			return filter, err
+		}
+		filter.SinceDate = &t
+	}
+	if token.UntilDate != "" {
+		t, err := time.Parse(time.RFC3339, token.UntilDate)
+		if err != nil {
+			return filter, err
+		}
+		filter.UntilDate = &t
+	}
+	return filter, nil
 }
 
 // GetMessages returns all messages from a user.
@@ -62,10 +223,50 @@ type pagingParams struct {
 //   type: integer
 // - name: since
 //   in: query
-//   description: return all messages with an ID less than this value
+//   description: 'Deprecated: use next_page_token instead. Return all messages with an ID less than this value'
 //   minimum: 0
 //   required: false
 //   type: integer
+// - name: next_page_token
+//   in: query
+//   description: opaque cursor from a previous response's paging.next_page_token, fetches the page it points to
+//   required: false
+//   type: string
+// - name: direction
+//   in: query
+//   description: sort direction for a fresh (non-cursor) request, one of asc or desc
+//   required: false
+//   type: string
+// - name: priority_min
+//   in: query
+//   description: only return messages with at least this priority
+//   required: false
+//   type: integer
+// - name: priority_max
+//   in: query
+//   description: only return messages with at most this priority
+//   required: false
+//   type: integer
+// - name: since_date
+//   in: query
+//   description: only return messages on or after this RFC3339 date
+//   required: false
+//   type: string
+// - name: until_date
+//   in: query
+//   description: only return messages on or before this RFC3339 date
+//   required: false
+//   type: string
+// - name: search
+//   in: query
+//   description: only return messages whose title or body contains this substring
+//   required: false
+//   type: string
+// - name: application_ids
+//   in: query
+//   description: comma-separated list of application ids to restrict the result to
+//   required: false
+//   type: string
 // responses:
 //   200:
 //     description: Ok
@@ -85,42 +286,178 @@ type pagingParams struct {
 //         $ref: "#/definitions/Error"
 func (a *MessageAPI) GetMessages(ctx *gin.Context) {
 	userID := auth.GetUserID(ctx)
-	withPaging(ctx, func(params *pagingParams) {
-		// the +1 is used to check if there are more messages and will be removed on buildWithPaging
-		messages, err := a.DB.GetMessagesByUserSince(userID, params.Limit+1, params.Since)
-		if success := successOrAbort(ctx, 500, err); !success {
-			return
-		}
-		ctx.JSON(200, buildWithPaging(ctx, params, messages))
+	a.withFilter(ctx, func(filter model.MessageFilter) {
+		a.withPaging(ctx, filter, func(params *pagingParams, filter model.MessageFilter) {
+			// the +1 is used to check if there are more messages and will be removed on buildWithPaging
+			messages, err := a.DB.GetMessagesFiltered(userID, params.Limit+1, params.Since, params.scanDirection, filter)
+			if success := successOrAbort(ctx, 500, err); !success {
+				return
+			}
+			ctx.JSON(200, a.buildWithPaging(ctx, params, filter, messages))
+		})
 	})
 }
 
-func buildWithPaging(ctx *gin.Context, paging *pagingParams, messages []*model.Message) *model.PagedMessages {
+// buildWithPaging cuts the +1 sentinel row off messages (if present),
+// normalizes a backward (prev_page_token) fetch back to the canonical
+// listing order, and builds the Paging block. It still fills in the
+// deprecated Since/Next fields for one release, alongside the new signed
+// NextPageToken/PrevPageToken, and echoes back the filter that was applied
+// so paginated navigation stays consistent.
+func (a *MessageAPI) buildWithPaging(ctx *gin.Context, paging *pagingParams, filter model.MessageFilter, messages []*model.Message) *model.PagedMessages {
 	next := ""
 	since := uint(0)
+	nextPageToken := ""
+	prevPageToken := ""
 	useMessages := messages
-	if len(messages) > paging.Limit {
+	hasMore := len(messages) > paging.Limit
+	if hasMore {
 		useMessages = messages[:len(messages)-1]
-		since = useMessages[len(useMessages)-1].ID
-		url := location.Get(ctx)
-		url.Path = ctx.Request.URL.Path
-		query := url.Query()
-		query.Add("limit", strconv.Itoa(paging.Limit))
-		query.Add("since", strconv.FormatUint(uint64(since), 10))
-		url.RawQuery = query.Encode()
-		next = url.String()
+	}
+	backward := paging.cursor != nil && paging.cursor.Backward
+	if backward {
+		// The rows were scanned in the opposite of the canonical direction
+		// to walk backwards; flip them back so every page, forward or
+		// backward, is returned in the same listing order and every token
+		// below is derived from that order's actual head/tail ids.
+		reverseMessages(useMessages)
+	}
+
+	if backward && len(useMessages) == 0 {
+		// Everything beyond the cursor was deleted since the page we
+		// navigated back from was built, but that page (anchored at
+		// paging.Since, the cursor's LastID) still exists - point forward
+		// to it instead of stranding the client on this empty page.
+		// next_page_token's LastID is exclusive of the id it carries, so
+		// nudge it one step past paging.Since in the scan direction or
+		// paging.Since itself would be skipped.
+		since = paging.Since
+		anchor := since + 1
+		if paging.Direction == pagination.DirectionAsc {
+			anchor = since - 1
+		}
+		token, err := pagination.Encode(a.PagingSecret, pagination.CursorToken{
+			LastID:    anchor,
+			Direction: paging.Direction,
+			Size:      paging.Limit,
+			Filter:    filterToToken(filter),
+		})
+		if success := successOrAbort(ctx, 500, err); !success {
+			return nil
+		}
+		nextPageToken = token
+	} else if len(useMessages) > 0 {
+		tailID := useMessages[len(useMessages)-1].ID
+		headID := useMessages[0].ID
+
+		// A forward scan that filled the +1 sentinel has more beyond tailID.
+		// A backward scan always has more beyond tailID too: it's the page
+		// we navigated back from.
+		if hasMore || backward {
+			since = tailID
+			if paging.cursor == nil {
+				// Keep the old since-link chain working for one release: a
+				// client that never sends next_page_token must still get a
+				// usable paging.next from page 1 onward, not just once it
+				// starts passing since itself.
+				url := location.Get(ctx)
+				url.Path = ctx.Request.URL.Path
+				query := url.Query()
+				query.Add("limit", strconv.Itoa(paging.Limit))
+				query.Add("since", strconv.FormatUint(uint64(since), 10))
+				url.RawQuery = query.Encode()
+				next = url.String()
+			}
+
+			token, err := pagination.Encode(a.PagingSecret, pagination.CursorToken{
+				LastID:    tailID,
+				Direction: paging.Direction,
+				Size:      paging.Limit,
+				Filter:    filterToToken(filter),
+			})
+			if success := successOrAbort(ctx, 500, err); !success {
+				return nil
+			}
+			nextPageToken = token
+		}
+
+		// A previous page exists if this page was itself reached by paging
+		// from somewhere (forward scan) or if the backward scan filled its
+		// own +1 sentinel (there's more further back than what we kept).
+		if (!backward && (paging.cursor != nil || paging.Since != 0)) || (backward && hasMore) {
+			token, err := pagination.Encode(a.PagingSecret, pagination.CursorToken{
+				LastID:    headID,
+				Direction: paging.Direction,
+				Backward:  true,
+				Size:      paging.Limit,
+				Filter:    filterToToken(filter),
+			})
+			if success := successOrAbort(ctx, 500, err); !success {
+				return nil
+			}
+			prevPageToken = token
+		}
+	}
+	var echoedFilter *model.MessageFilter
+	if !filter.IsEmpty() {
+		echoedFilter = &filter
 	}
 	return &model.PagedMessages{
-		Paging:   model.Paging{Size: len(useMessages), Limit: paging.Limit, Next: next, Since: since},
+		Paging:   model.Paging{Size: len(useMessages), Limit: paging.Limit, Next: next, Since: since, NextPageToken: nextPageToken, PrevPageToken: prevPageToken, Filter: echoedFilter},
 		Messages: toExternalMessages(useMessages),
 	}
 }
 
-func withPaging(ctx *gin.Context, f func(pagingParams *pagingParams)) {
+// reverseMessages reverses messages in place.
+func reverseMessages(messages []*model.Message) {
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+}
+
+// withPaging binds pagingParams from the query string. If a next_page_token
+// is present, it is decoded and verified, and overrides the deprecated
+// since/limit/direction params as well as filter (the one baked into the
+// token when the first page was built) so a page stays self-consistent with
+// the one that produced its cursor. A backward (prev_page_token) cursor
+// scans the opposite of its canonical Direction; buildWithPaging reverses
+// the result back before using it.
+func (a *MessageAPI) withPaging(ctx *gin.Context, filter model.MessageFilter, f func(params *pagingParams, filter model.MessageFilter)) {
 	params := &pagingParams{Limit: 100}
-	if err := ctx.MustBindWith(params, binding.Query); err == nil {
-		f(params)
+	if err := ctx.MustBindWith(params, binding.Query); err != nil {
+		return
 	}
+	if params.NextPageToken != "" {
+		token, err := pagination.Decode(a.PagingSecret, params.NextPageToken)
+		if err != nil {
+			ctx.AbortWithError(400, err)
+			return
+		}
+		params.cursor = &token
+		params.Since = token.LastID
+		params.Limit = token.Size
+		params.Direction = token.Direction
+		params.scanDirection = token.Direction
+		if token.Backward {
+			params.scanDirection = oppositeDirection(token.Direction)
+		}
+		tokenFilter, err := filterFromToken(token.Filter)
+		if success := successOrAbort(ctx, 500, err); !success {
+			return
+		}
+		filter = tokenFilter
+	} else {
+		if params.Direction == "" {
+			params.Direction = pagination.DirectionDesc
+		}
+		params.scanDirection = params.Direction
+		if params.Since != 0 {
+			// Deprecated: clients still using since/limit get a notice so they
+			// know to migrate to next_page_token before it goes away.
+			ctx.Header("Deprecation", "true")
+		}
+	}
+	f(params, filter)
 }
 
 // GetMessagesWithApplication returns all messages from a specific application.
@@ -147,10 +484,50 @@ func withPaging(ctx *gin.Context, f func(pagingParams *pagingParams)) {
 //   type: integer
 // - name: since
 //   in: query
-//   description: return all messages with an ID less than this value
+//   description: 'Deprecated: use next_page_token instead. Return all messages with an ID less than this value'
 //   minimum: 0
 //   required: false
 //   type: integer
+// - name: next_page_token
+//   in: query
+//   description: opaque cursor from a previous response's paging.next_page_token, fetches the page it points to
+//   required: false
+//   type: string
+// - name: direction
+//   in: query
+//   description: sort direction for a fresh (non-cursor) request, one of asc or desc
+//   required: false
+//   type: string
+// - name: priority_min
+//   in: query
+//   description: only return messages with at least this priority
+//   required: false
+//   type: integer
+// - name: priority_max
+//   in: query
+//   description: only return messages with at most this priority
+//   required: false
+//   type: integer
+// - name: since_date
+//   in: query
+//   description: only return messages on or after this RFC3339 date
+//   required: false
+//   type: string
+// - name: until_date
+//   in: query
+//   description: only return messages on or before this RFC3339 date
+//   required: false
+//   type: string
+// - name: search
+//   in: query
+//   description: only return messages whose title or body contains this substring
+//   required: false
+//   type: string
+// - name: application_ids
+//   in: query
+//   description: comma-separated list of application ids to restrict the result to
+//   required: false
+//   type: string
 // responses:
 //   200:
 //     description: Ok
@@ -174,21 +551,23 @@ func withPaging(ctx *gin.Context, f func(pagingParams *pagingParams)) {
 //         $ref: "#/definitions/Error"
 func (a *MessageAPI) GetMessagesWithApplication(ctx *gin.Context) {
 	withID(ctx, "id", func(id uint) {
-		withPaging(ctx, func(params *pagingParams) {
-			app, err := a.DB.GetApplicationByID(id)
-			if success := successOrAbort(ctx, 500, err); !success {
-				return
-			}
-			if app != nil && app.UserID == auth.GetUserID(ctx) {
-				// the +1 is used to check if there are more messages and will be removed on buildWithPaging
-				messages, err := a.DB.GetMessagesByApplicationSince(id, params.Limit+1, params.Since)
+		a.withFilter(ctx, func(filter model.MessageFilter) {
+			a.withPaging(ctx, filter, func(params *pagingParams, filter model.MessageFilter) {
+				app, err := a.DB.GetApplicationByID(id)
 				if success := successOrAbort(ctx, 500, err); !success {
 					return
 				}
-				ctx.JSON(200, buildWithPaging(ctx, params, messages))
-			} else {
-				ctx.AbortWithError(404, errors.New("application does not exist"))
-			}
+				if app != nil && app.UserID == auth.GetUserID(ctx) {
+					// the +1 is used to check if there are more messages and will be removed on buildWithPaging
+					messages, err := a.DB.GetMessagesByApplicationFiltered(id, params.Limit+1, params.Since, params.scanDirection, filter)
+					if success := successOrAbort(ctx, 500, err); !success {
+						return
+					}
+					ctx.JSON(200, a.buildWithPaging(ctx, params, filter, messages))
+				} else {
+					ctx.AbortWithError(404, errors.New("application does not exist"))
+				}
+			})
 		})
 	})
 }
@@ -350,6 +729,11 @@ func (a *MessageAPI) DeleteMessage(ctx *gin.Context) {
 // produces: [application/json]
 // security: [appTokenHeader: [], appTokenQuery: []]
 // parameters:
+// - name: Idempotency-Key
+//   in: header
+//   description: if a message was already created with this key (for this application), that message is returned unchanged instead of creating a duplicate. Can also be supplied as the idempotency_key body field.
+//   required: false
+//   type: string
 // - name: body
 //   in: body
 //   description: the message to add
@@ -382,17 +766,228 @@ func (a *MessageAPI) CreateMessage(ctx *gin.Context) {
 	if success := successOrAbort(ctx, 500, err); !success {
 		return
 	}
-	message := appMessage.ToInternal(application.ID)
+
+	idempotencyKey := ctx.GetHeader("Idempotency-Key")
+	if idempotencyKey == "" {
+		idempotencyKey = appMessage.IdempotencyKey
+	}
+	if idempotencyKey != "" {
+		existing, err := a.DB.GetMessageByIdempotencyKey(application.ID, idempotencyKey)
+		if success := successOrAbort(ctx, 500, err); !success {
+			return
+		}
+		if existing != nil {
+			ctx.JSON(200, existing.ToExternal())
+			return
+		}
+	}
+
+	message, err := appMessage.ToInternal(application.ID, application.DefaultRequiresAck)
+	if err != nil {
+		ctx.AbortWithError(400, err)
+		return
+	}
+	if message.Cron != "" {
+		if _, err := scheduler.ParseCron(message.Cron); err != nil {
+			ctx.AbortWithError(400, err)
+			return
+		}
+	}
 	if strings.TrimSpace(message.Title) == "" {
 		message.Title = application.Name
 	}
 	if success := successOrAbort(ctx, 500, a.DB.CreateMessage(message)); !success {
 		return
 	}
-	a.Notifier.Notify(auth.GetUserID(ctx), message)
+	if idempotencyKey != "" {
+		expiresAt := time.Now().Add(idempotencyKeyTTL)
+		if success := successOrAbort(ctx, 500, a.DB.StoreIdempotencyKey(application.ID, idempotencyKey, message.ID, expiresAt)); !success {
+			return
+		}
+	}
+	// Scheduled messages are delivered (and notified) by the scheduler once due.
+	if message.State != model.MessageStateScheduled {
+		a.Notifier.Notify(auth.GetUserID(ctx), message)
+	}
 	ctx.JSON(200, message.ToExternal())
 }
 
+// GetScheduledMessages returns all pending scheduled messages for a user.
+// swagger:operation GET /message/scheduled message getScheduledMessages
+//
+// Return all pending scheduled messages.
+//
+// ---
+// produces: [application/json]
+// security: [clientTokenHeader: [], clientTokenQuery: [], basicAuth: []]
+// responses:
+//   200:
+//     description: Ok
+//     schema:
+//       type: array
+//       items:
+//         $ref: "#/definitions/Message"
+//   401:
+//     description: Unauthorized
+//     schema:
+//         $ref: "#/definitions/Error"
+//   403:
+//     description: Forbidden
+//     schema:
+//         $ref: "#/definitions/Error"
+func (a *MessageAPI) GetScheduledMessages(ctx *gin.Context) {
+	userID := auth.GetUserID(ctx)
+	messages, err := a.DB.GetScheduledMessagesByUser(userID)
+	if success := successOrAbort(ctx, 500, err); !success {
+		return
+	}
+	ctx.JSON(200, toExternalMessages(messages))
+}
+
+// CancelScheduledMessage cancels a pending scheduled message before it's delivered.
+// swagger:operation DELETE /message/scheduled/{id} message deleteScheduledMessage
+//
+// Deletes a pending scheduled message with an id.
+//
+// ---
+// produces: [application/json]
+// security: [clientTokenHeader: [], clientTokenQuery: [], basicAuth: []]
+// parameters:
+// - name: id
+//   in: path
+//   description: the message id
+//   required: true
+//   type: integer
+// responses:
+//   200:
+//     description: Ok
+//   400:
+//     description: Bad Request
+//     schema:
+//         $ref: "#/definitions/Error"
+//   401:
+//     description: Unauthorized
+//     schema:
+//         $ref: "#/definitions/Error"
+//   403:
+//     description: Forbidden
+//     schema:
+//         $ref: "#/definitions/Error"
+//   404:
+//     description: Not Found
+//     schema:
+//         $ref: "#/definitions/Error"
+func (a *MessageAPI) CancelScheduledMessage(ctx *gin.Context) {
+	withID(ctx, "id", func(id uint) {
+		msg, err := a.DB.GetMessageByID(id)
+		if success := successOrAbort(ctx, 500, err); !success {
+			return
+		}
+		if msg == nil || msg.State != model.MessageStateScheduled {
+			ctx.AbortWithError(404, errors.New("scheduled message does not exist"))
+			return
+		}
+		app, err := a.DB.GetApplicationByID(msg.ApplicationID)
+		if success := successOrAbort(ctx, 500, err); !success {
+			return
+		}
+		if app == nil || app.UserID != auth.GetUserID(ctx) {
+			ctx.AbortWithError(404, errors.New("scheduled message does not exist"))
+			return
+		}
+		successOrAbort(ctx, 500, a.DB.DeleteMessageByID(id))
+	})
+}
+
+// GetUnreadMessages returns all requires_ack messages the user hasn't
+// acknowledged yet. This is the redelivery path for requires_ack messages: a
+// client that reconnects (e.g. after its WebSocket stream was down) calls
+// this once to catch up, then acks each message as it handles it, instead of
+// relying on the stream to redeliver anything it missed while disconnected.
+// swagger:operation GET /message/unread message getUnreadMessages
+//
+// Return all unacknowledged messages.
+//
+// ---
+// produces: [application/json]
+// security: [clientTokenHeader: [], clientTokenQuery: [], basicAuth: []]
+// responses:
+//   200:
+//     description: Ok
+//     schema:
+//       type: array
+//       items:
+//         $ref: "#/definitions/Message"
+//   401:
+//     description: Unauthorized
+//     schema:
+//         $ref: "#/definitions/Error"
+//   403:
+//     description: Forbidden
+//     schema:
+//         $ref: "#/definitions/Error"
+func (a *MessageAPI) GetUnreadMessages(ctx *gin.Context) {
+	userID := auth.GetUserID(ctx)
+	messages, err := a.DB.GetUnackedMessagesByUser(userID)
+	if success := successOrAbort(ctx, 500, err); !success {
+		return
+	}
+	ctx.JSON(200, toExternalMessages(messages))
+}
+
+// AckMessage acknowledges a requires_ack message, removing it from the
+// user's unacked queue.
+// swagger:operation PUT /message/{id}/ack message ackMessage
+//
+// Acknowledge a message.
+//
+// ---
+// produces: [application/json]
+// security: [clientTokenHeader: [], clientTokenQuery: [], basicAuth: []]
+// parameters:
+// - name: id
+//   in: path
+//   description: the message id
+//   required: true
+//   type: integer
+// responses:
+//   200:
+//     description: Ok
+//   401:
+//     description: Unauthorized
+//     schema:
+//         $ref: "#/definitions/Error"
+//   403:
+//     description: Forbidden
+//     schema:
+//         $ref: "#/definitions/Error"
+//   404:
+//     description: Not Found
+//     schema:
+//         $ref: "#/definitions/Error"
+func (a *MessageAPI) AckMessage(ctx *gin.Context) {
+	withID(ctx, "id", func(id uint) {
+		msg, err := a.DB.GetMessageByID(id)
+		if success := successOrAbort(ctx, 500, err); !success {
+			return
+		}
+		if msg == nil {
+			ctx.AbortWithError(404, errors.New("message does not exist"))
+			return
+		}
+		app, err := a.DB.GetApplicationByID(msg.ApplicationID)
+		if success := successOrAbort(ctx, 500, err); !success {
+			return
+		}
+		userID := auth.GetUserID(ctx)
+		if app == nil || app.UserID != userID {
+			ctx.AbortWithError(404, errors.New("message does not exist"))
+			return
+		}
+		successOrAbort(ctx, 500, a.DB.AckMessage(userID, id))
+	})
+}
+
 func toExternalMessages(msg []*model.Message) []*model.MessageExternal {
 	res := make([]*model.MessageExternal, len(msg))
 	for i := range msg {
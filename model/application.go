@@ -0,0 +1,12 @@
+package model
+
+// Application is the entity that sends messages, identified by its token.
+type Application struct {
+	ID     uint `gorm:"primary_key;auto_increment"`
+	UserID uint `gorm:"index"`
+	Name   string
+	// DefaultRequiresAck is the requires_ack used for messages from this
+	// application that don't set their own requires_ack (see
+	// ApplicationMessage.RequiresAck).
+	DefaultRequiresAck bool
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// MessageFilter holds the optional predicates that GetMessagesFiltered and
+// GetMessagesByApplicationFiltered compose into SQL, rather than filtering
+// in Go after the fact.
+type MessageFilter struct {
+	// PriorityMin, if set, excludes messages with a lower priority.
+	PriorityMin *int `json:"priority_min,omitempty"`
+	// PriorityMax, if set, excludes messages with a higher priority.
+	PriorityMax *int `json:"priority_max,omitempty"`
+	// SinceDate, if set, excludes messages older than this date.
+	SinceDate *time.Time `json:"since_date,omitempty"`
+	// UntilDate, if set, excludes messages newer than this date.
+	UntilDate *time.Time `json:"until_date,omitempty"`
+	// Search, if set, matches a substring of the message title or body.
+	Search string `json:"search,omitempty"`
+	// ApplicationIDs, if set, restricts the result to these applications.
+	ApplicationIDs []uint `json:"application_ids,omitempty"`
+}
+
+// IsEmpty reports whether no predicate is set, i.e. the filter is a no-op.
+func (f MessageFilter) IsEmpty() bool {
+	return f.PriorityMin == nil && f.PriorityMax == nil && f.SinceDate == nil &&
+		f.UntilDate == nil && f.Search == "" && len(f.ApplicationIDs) == 0
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// IdempotencyKey maps a client-supplied idempotency key, scoped to an
+// application, to the message it originally created. Entries expire after a
+// TTL so the table doesn't grow unbounded.
+type IdempotencyKey struct {
+	ApplicationID uint   `gorm:"primary_key"`
+	Key           string `gorm:"primary_key"`
+	MessageID     uint
+	ExpiresAt     time.Time
+}
@@ -0,0 +1,275 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// maxExtrasSize caps the serialized size of a message's Extras, so a client
+// can't blow up storage or the WebSocket event payload with an oversized map.
+const maxExtrasSize = 4096
+
+// extraKeyPattern matches the reverse-DNS namespaced extras keys used by
+// Gotify/SimpleCloudNotifier clients and plugins, e.g.
+// "client::display::markdown" or "android::action::onclick".
+var extraKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9_.]+(::[a-zA-Z0-9_.]+)+$`)
+
+// Message states. A message without SendAt is created directly in
+// MessageStateDelivered; one with a future SendAt starts out
+// MessageStateScheduled and is flipped to MessageStateDelivered (and
+// notified) by the scheduler once it's due.
+const (
+	MessageStateDelivered = "delivered"
+	MessageStateScheduled = "scheduled"
+)
+
+// Message represents a message that was sent by an application.
+type Message struct {
+	ID            uint `gorm:"primary_key;auto_increment"`
+	ApplicationID uint `gorm:"index"`
+	Message       string
+	Title         string
+	Priority      int
+	Date          time.Time
+	// State is one of the Message* state constants.
+	State string
+	// SendAt is when the message should be delivered. Nil means deliver
+	// immediately. For a recurring (Cron) message, it holds the next
+	// occurrence.
+	SendAt *time.Time
+	// Cron is a standard 5-field cron expression for recurring messages. If
+	// set, the message is redelivered and rescheduled for its next
+	// occurrence each time SendAt comes due, instead of being retired.
+	Cron string
+	// RequiresAck opts the message into ack-tracking: it stays in the
+	// owning user's unacked queue (see GetUnackedMessagesByUser) until
+	// explicitly acknowledged.
+	RequiresAck bool
+	// ClickAction is the URL opened when the user taps the notification.
+	ClickAction string
+	// ExpiresAt, if set, is when the message is no longer relevant and
+	// clients should stop showing/pushing it.
+	ExpiresAt *time.Time
+	// Extras holds typed, reverse-DNS namespaced client/plugin data (e.g.
+	// "client::display::markdown"), the same pattern Gotify plugins and
+	// SimpleCloudNotifier use to let clients render richer notifications
+	// without breaking older clients that ignore unknown keys.
+	Extras map[string]interface{} `gorm:"-"`
+	// RawExtras is the JSON-serialized Extras, persisted in the "extras" column.
+	RawExtras []byte `json:"-" gorm:"column:extras"`
+}
+
+// BeforeSave serializes Extras into RawExtras for storage.
+func (m *Message) BeforeSave() error {
+	if len(m.Extras) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(m.Extras)
+	if err != nil {
+		return err
+	}
+	m.RawExtras = raw
+	return nil
+}
+
+// AfterFind deserializes RawExtras back into Extras.
+func (m *Message) AfterFind() error {
+	if len(m.RawExtras) == 0 {
+		return nil
+	}
+	return json.Unmarshal(m.RawExtras, &m.Extras)
+}
+
+// Event is the payload that gets sent through the Notifier. Besides Message
+// itself, other event types (e.g. MessageDeletions) implement it too.
+type Event interface{}
+
+// MessageExternal is the Message model that gets exposed via the API.
+type MessageExternal struct {
+	ID            uint                   `json:"id"`
+	ApplicationID uint                   `json:"appid"`
+	Message       string                 `json:"message"`
+	Title         string                 `json:"title"`
+	Priority      int                    `json:"priority"`
+	Date          time.Time              `json:"date"`
+	State         string                 `json:"state,omitempty"`
+	SendAt        *time.Time             `json:"send_at,omitempty"`
+	Cron          string                 `json:"cron,omitempty"`
+	RequiresAck   bool                   `json:"requires_ack,omitempty"`
+	ClickAction   string                 `json:"click_action,omitempty"`
+	ExpiresAt     *time.Time             `json:"expires_at,omitempty"`
+	Extras        map[string]interface{} `json:"extras,omitempty"`
+}
+
+// ToExternal converts a Message to its external representation.
+func (m *Message) ToExternal() Event {
+	return &MessageExternal{
+		ID:            m.ID,
+		ApplicationID: m.ApplicationID,
+		Message:       m.Message,
+		Title:         m.Title,
+		Priority:      m.Priority,
+		Date:          m.Date,
+		State:         m.State,
+		SendAt:        m.SendAt,
+		Cron:          m.Cron,
+		RequiresAck:   m.RequiresAck,
+		ClickAction:   m.ClickAction,
+		ExpiresAt:     m.ExpiresAt,
+		Extras:        m.Extras,
+	}
+}
+
+// MessageDeletions holds the messages that got deleted. It is sent as an
+// event through the Notifier so connected clients can remove them too.
+type MessageDeletions struct {
+	Messages []*Message `json:"messages"`
+}
+
+// ApplicationMessage represents a message that can be created by a client.
+//
+// swagger:model Message
+type ApplicationMessage struct {
+	// The message.
+	//
+	// required: true
+	// example: Lorem ipsum dolor sit amet
+	Message string `form:"message" query:"message" json:"message" binding:"required"`
+	// The title of the message, if empty the title is set to the name of the application.
+	//
+	// example: Backup
+	Title string `form:"title" query:"title" json:"title"`
+	// The priority of the message, 0-10.
+	//
+	// example: 4
+	Priority int `form:"priority" query:"priority" json:"priority"`
+	// An idempotency key identifying this message. A repeated create with
+	// the same key (within an application) returns the original message
+	// instead of creating a duplicate. Can also be supplied via the
+	// Idempotency-Key header, which takes precedence.
+	//
+	// example: a0e2a8c2-4b7a-4b3a-9b3a-1f9d3c2a9b3e
+	IdempotencyKey string `form:"idempotency_key" query:"idempotency_key" json:"idempotency_key,omitempty"`
+	// When set (RFC3339), the message is delivered at this time instead of
+	// immediately. A send_at that isn't in the future is delivered
+	// immediately, unless Cron is set, in which case it's rejected.
+	//
+	// example: 2021-01-01T00:00:00Z
+	SendAt string `form:"send_at" query:"send_at" json:"send_at,omitempty"`
+	// A standard 5-field cron expression. If set, SendAt anchors the first
+	// occurrence and the message is redelivered and rescheduled for its
+	// next occurrence each time it comes due.
+	//
+	// example: */15 * * * *
+	Cron string `form:"cron" query:"cron" json:"cron,omitempty"`
+	// When true, the message stays in the owning user's unacked queue
+	// (GET /message/unread) until acknowledged via PUT /message/{id}/ack,
+	// so a client that was offline still sees it once it reconnects. Falls
+	// back to the sending application's DefaultRequiresAck when omitted.
+	//
+	// example: false
+	RequiresAck *bool `form:"requires_ack" query:"requires_ack" json:"requires_ack,omitempty"`
+	// A URL opened when the user taps the notification.
+	//
+	// example: https://example.com
+	ClickAction string `form:"click_action" query:"click_action" json:"click_action,omitempty"`
+	// When set (RFC3339), clients should stop showing/pushing the message after this time.
+	//
+	// example: 2021-01-02T00:00:00Z
+	ExpiresAt string `form:"expires_at" query:"expires_at" json:"expires_at,omitempty"`
+	// Typed extras, keyed by reverse-DNS namespace (e.g.
+	// "client::display::markdown", "android::action::onclick"). Unknown
+	// keys are ignored by clients that don't understand them.
+	Extras map[string]interface{} `form:"-" query:"-" json:"extras,omitempty"`
+}
+
+// ToInternal converts an ApplicationMessage to a Message. defaultRequiresAck
+// is the sending application's DefaultRequiresAck, used when RequiresAck
+// isn't set on the message itself. ToInternal returns an error if Priority is
+// outside 0-10, if SendAt/ExpiresAt are set but aren't valid RFC3339
+// timestamps, if Cron is set without SendAt to anchor its first occurrence,
+// if ClickAction isn't a valid absolute URL, or if Extras has an invalid key
+// or exceeds maxExtrasSize when serialized.
+func (a ApplicationMessage) ToInternal(appID uint, defaultRequiresAck bool) (*Message, error) {
+	if a.Priority < 0 || a.Priority > 10 {
+		return nil, fmt.Errorf("priority: must be between 0 and 10")
+	}
+	if a.ClickAction != "" {
+		u, err := url.Parse(a.ClickAction)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("click_action: must be an absolute URL")
+		}
+	}
+	if err := validateExtras(a.Extras); err != nil {
+		return nil, err
+	}
+
+	requiresAck := defaultRequiresAck
+	if a.RequiresAck != nil {
+		requiresAck = *a.RequiresAck
+	}
+	message := &Message{
+		ApplicationID: appID,
+		Message:       a.Message,
+		Title:         a.Title,
+		Priority:      a.Priority,
+		Date:          time.Now(),
+		State:         MessageStateDelivered,
+		Cron:          a.Cron,
+		RequiresAck:   requiresAck,
+		ClickAction:   a.ClickAction,
+		Extras:        a.Extras,
+	}
+	if a.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, a.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("expires_at: %s", err)
+		}
+		message.ExpiresAt = &expiresAt
+	}
+	if a.SendAt == "" {
+		if a.Cron != "" {
+			return nil, errors.New("cron requires send_at to anchor its first occurrence")
+		}
+		return message, nil
+	}
+	sendAt, err := time.Parse(time.RFC3339, a.SendAt)
+	if err != nil {
+		return nil, fmt.Errorf("send_at: %s", err)
+	}
+	if !sendAt.After(time.Now()) {
+		if a.Cron != "" {
+			return nil, errors.New("cron: send_at must be in the future to anchor its first occurrence")
+		}
+		message.SendAt = &sendAt
+		return message, nil
+	}
+	message.SendAt = &sendAt
+	message.State = MessageStateScheduled
+	return message, nil
+}
+
+// validateExtras checks that every key is a reverse-DNS namespace and that
+// the serialized map doesn't exceed maxExtrasSize.
+func validateExtras(extras map[string]interface{}) error {
+	if len(extras) == 0 {
+		return nil
+	}
+	for key := range extras {
+		if !extraKeyPattern.MatchString(key) {
+			return fmt.Errorf("extras: invalid key %q, expected a reverse-DNS namespace like \"client::display::markdown\"", key)
+		}
+	}
+	raw, err := json.Marshal(extras)
+	if err != nil {
+		return fmt.Errorf("extras: %s", err)
+	}
+	if len(raw) > maxExtrasSize {
+		return fmt.Errorf("extras: serialized size %d exceeds the %d byte limit", len(raw), maxExtrasSize)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package model
+
+// Paging contains information about the paged items.
+type Paging struct {
+	// the amount of messages returned
+	//
+	// required: true
+	// example: 100
+	Size int `json:"size"`
+	// the maximal amount of messages to return
+	//
+	// required: true
+	// example: 100
+	Limit int `json:"limit"`
+	// Deprecated: use next_page_token instead. The id of the next message, only included when there are still messages left.
+	//
+	// example: 2
+	Since uint `json:"since,omitempty"`
+	// Deprecated: use next_page_token instead. The link to the next page of messages, only included when there are still messages left.
+	//
+	// example: http://example.com/message?limit=100&since=2
+	Next string `json:"next,omitempty"`
+	// Opaque, signed cursor for fetching the next page. Self-consistent: it
+	// carries the sort direction and any filters that were applied so that
+	// subsequent pages don't drift from the first one.
+	NextPageToken string `json:"next_page_token,omitempty"`
+	// Opaque, signed cursor for fetching the previous page.
+	PrevPageToken string `json:"prev_page_token,omitempty"`
+	// Filter is the filter that was applied to produce this page, echoed
+	// back so paginated navigation stays consistent across pages.
+	Filter *MessageFilter `json:"filter,omitempty"`
+}
+
+// PagedMessages contains the messages and information about paging.
+type PagedMessages struct {
+	Paging   Paging             `json:"paging"`
+	Messages []*MessageExternal `json:"messages"`
+}
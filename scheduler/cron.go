@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed, standard 5-field cron expression (minute hour
+// day-of-month month day-of-week).
+type CronSchedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	// domRestricted/dowRestricted record whether the day-of-month/day-of-week
+	// field was anything other than "*". Standard cron ORs dom and dow when
+	// both are restricted, instead of the usual AND, so e.g. "0 0 13 * 5"
+	// fires on the 13th of the month or on any Friday.
+	domRestricted, dowRestricted bool
+}
+
+// ParseCron parses a standard 5-field cron expression. Each field accepts
+// "*", single values, lists ("1,2,3"), ranges ("1-5") and step values
+// ("*/15", "1-30/10"). Day-of-week accepts 0-7, where both 0 and 7 mean
+// Sunday.
+func ParseCron(expr string) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron: expected 5 fields, got %d", len(fields))
+	}
+	minute, _, err := parseCronField(fields[0], 0, 59)
+	if err != nil {
+		return nil, err
+	}
+	hour, _, err := parseCronField(fields[1], 0, 23)
+	if err != nil {
+		return nil, err
+	}
+	dom, domRestricted, err := parseCronField(fields[2], 1, 31)
+	if err != nil {
+		return nil, err
+	}
+	month, _, err := parseCronField(fields[3], 1, 12)
+	if err != nil {
+		return nil, err
+	}
+	dow, dowRestricted, err := parseCronField(fields[4], 0, 7)
+	if err != nil {
+		return nil, err
+	}
+	if dow[7] {
+		dow[0] = true
+		delete(dow, 7)
+	}
+	return &CronSchedule{
+		minute: minute, hour: hour, dom: dom, month: month, dow: dow,
+		domRestricted: domRestricted, dowRestricted: dowRestricted,
+	}, nil
+}
+
+// parseCronField parses a single cron field and also reports whether the
+// field was restricted, i.e. anything other than the literal "*".
+func parseCronField(field string, min, max int) (map[int]bool, bool, error) {
+	values := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		base, step := part, 1
+		if idx := strings.IndexByte(part, '/'); idx != -1 {
+			s, err := strconv.Atoi(part[idx+1:])
+			if err != nil || s <= 0 {
+				return nil, false, fmt.Errorf("cron: invalid step %q", part)
+			}
+			step = s
+			base = part[:idx]
+		}
+
+		lo, hi := min, max
+		switch {
+		case base == "*":
+			// lo/hi already cover the full range
+		case strings.Contains(base, "-"):
+			bounds := strings.SplitN(base, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, false, fmt.Errorf("cron: invalid range %q", base)
+			}
+			if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, false, fmt.Errorf("cron: invalid range %q", base)
+			}
+		default:
+			v, err := strconv.Atoi(base)
+			if err != nil {
+				return nil, false, fmt.Errorf("cron: invalid value %q", base)
+			}
+			lo, hi = v, v
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, false, fmt.Errorf("cron: value %q out of range [%d,%d]", base, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			values[v] = true
+		}
+	}
+	return values, field != "*", nil
+}
+
+// Next returns the next minute-resolution time strictly after from that
+// matches the schedule. It returns an error if it searches eight years out
+// (the longest gap between two Feb 29ths) without a match, which only
+// happens for an impossible schedule such as "0 0 30 2 *" (Feb 30th never
+// occurs).
+func (c *CronSchedule) Next(from time.Time) (time.Time, error) {
+	t := from.Truncate(time.Minute).Add(time.Minute)
+	for i := 0; i < 8*366*24*60; i++ {
+		domMatch := c.dom[t.Day()]
+		dowMatch := c.dow[int(t.Weekday())]
+		// Standard cron: when both dom and dow are restricted, either one
+		// matching is enough; otherwise (at most one restricted) both must
+		// match, which degrades to "whichever is restricted must match"
+		// since an unrestricted field matches everything.
+		dayMatch := domMatch && dowMatch
+		if c.domRestricted && c.dowRestricted {
+			dayMatch = domMatch || dowMatch
+		}
+		if c.minute[t.Minute()] && c.hour[t.Hour()] && c.month[int(t.Month())] && dayMatch {
+			return t, nil
+		}
+		t = t.Add(time.Minute)
+	}
+	return time.Time{}, fmt.Errorf("cron: schedule never occurs")
+}
@@ -0,0 +1,106 @@
+// Package scheduler delivers messages that were created with a future
+// send_at, and reschedules recurring (Cron) ones for their next occurrence.
+package scheduler
+
+import (
+	"log"
+	"time"
+
+	"github.com/gotify/server/model"
+)
+
+// defaultInterval is how often Run polls for due messages when New is
+// called with interval <= 0.
+const defaultInterval = 30 * time.Second
+
+// batchSize caps how many due messages are delivered per poll.
+const batchSize = 100
+
+// Database is the subset of persistence operations the scheduler needs. Any
+// type implementing api.MessageDatabase satisfies it, so the server can hand
+// the same database value to both.
+type Database interface {
+	GetDueScheduledMessages(now time.Time, limit int) ([]*model.Message, error)
+	MarkMessageDelivered(id uint) error
+	ScheduleNextOccurrence(id uint, nextSendAt time.Time) error
+	GetApplicationByID(id uint) (*model.Application, error)
+}
+
+// Notifier notifies a user's connected clients of a new message.
+type Notifier interface {
+	Notify(userID uint, event model.Event)
+}
+
+// Scheduler polls for due scheduled messages and delivers them.
+type Scheduler struct {
+	DB       Database
+	Notifier Notifier
+	Interval time.Duration
+}
+
+// New creates a Scheduler that polls every interval for due messages.
+// interval <= 0 defaults to 30s.
+func New(db Database, notifier Notifier, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Scheduler{DB: db, Notifier: notifier, Interval: interval}
+}
+
+// Run polls for due messages every s.Interval until stop is closed. It's
+// meant to be started as a goroutine at application boot.
+func (s *Scheduler) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.deliverDue()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) deliverDue() {
+	messages, err := s.DB.GetDueScheduledMessages(time.Now(), batchSize)
+	if err != nil {
+		log.Printf("scheduler: failed to load due messages: %s", err)
+		return
+	}
+	for _, message := range messages {
+		app, err := s.DB.GetApplicationByID(message.ApplicationID)
+		if err != nil || app == nil {
+			log.Printf("scheduler: dropping message %d, application %d not found", message.ID, message.ApplicationID)
+			continue
+		}
+
+		if message.Cron != "" {
+			schedule, err := ParseCron(message.Cron)
+			if err != nil {
+				log.Printf("scheduler: message %d has invalid cron %q: %s", message.ID, message.Cron, err)
+				continue
+			}
+			next, err := schedule.Next(time.Now())
+			if err != nil {
+				// The schedule can never occur again (e.g. "0 0 30 2 *"):
+				// deliver this once and stop recurring, instead of
+				// rescheduling to ~now and redelivering on every poll.
+				log.Printf("scheduler: message %d cron %q never occurs again, delivering once: %s", message.ID, message.Cron, err)
+				if err := s.DB.MarkMessageDelivered(message.ID); err != nil {
+					log.Printf("scheduler: failed to mark message %d delivered: %s", message.ID, err)
+					continue
+				}
+			} else if err := s.DB.ScheduleNextOccurrence(message.ID, next); err != nil {
+				log.Printf("scheduler: failed to reschedule message %d: %s", message.ID, err)
+				continue
+			}
+		} else if err := s.DB.MarkMessageDelivered(message.ID); err != nil {
+			log.Printf("scheduler: failed to mark message %d delivered: %s", message.ID, err)
+			continue
+		}
+
+		message.State = model.MessageStateDelivered
+		s.Notifier.Notify(app.UserID, message)
+	}
+}